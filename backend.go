@@ -0,0 +1,136 @@
+package nfsexports
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Backend implements the platform-specific pieces of managing an exports
+// file: checking that a raw export line matches the dialect the running
+// NFS server expects, verifying a full exports file before it is written,
+// and telling the server to pick up the new file.
+type Backend interface {
+	// ValidateSyntax performs a lightweight, dialect-specific sanity check
+	// of a single raw export line, so obviously malformed input is
+	// rejected before the external verifier is ever invoked.
+	ValidateSyntax(line string) error
+	// Verify checks whether newContents is a valid exports file, without
+	// writing it to disk.
+	Verify(ctx context.Context, newContents []byte) error
+	// Reload tells the NFS server to pick up the on-disk exports file.
+	Reload(ctx context.Context) error
+	// Syntax is the ExportSyntax dialect this Backend's server expects.
+	// Client and Transaction use it to resolve an Export left at
+	// UnspecifiedSyntax before serializing it.
+	Syntax() ExportSyntax
+}
+
+// DarwinNFSD is the Backend for macOS, driving the BSD nfsd command.
+type DarwinNFSD struct {
+	Fs     Fs
+	Runner CommandRunner
+}
+
+// NewDarwinNFSD returns the Backend used on macOS.
+func NewDarwinNFSD(fs Fs, runner CommandRunner) Backend {
+	return &DarwinNFSD{Fs: fs, Runner: runner}
+}
+
+// ValidateSyntax implements Backend.
+func (DarwinNFSD) ValidateSyntax(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return fmt.Errorf("export line is empty")
+	}
+
+	for _, field := range fields[1:] {
+		if strings.ContainsAny(field, "()") {
+			return fmt.Errorf("export line %q looks like Linux exportfs syntax, not BSD", line)
+		}
+	}
+
+	return nil
+}
+
+// Verify implements Backend by writing newContents to a temp file and
+// running `nfsd checkexports` against it.
+func (b *DarwinNFSD) Verify(ctx context.Context, newContents []byte) error {
+	tmpFile, err := b.Fs.TempFile("", "exports")
+	if err != nil {
+		return err
+	}
+	defer tmpFile.Close()
+
+	if _, err := tmpFile.Write(newContents); err != nil {
+		return err
+	}
+	tmpFile.Close()
+
+	if _, err := b.Runner.Run(ctx, "/sbin/nfsd", "-F", tmpFile.Name(), "checkexports"); err != nil {
+		return fmt.Errorf("Export verification failed:\n%w", err)
+	}
+
+	return nil
+}
+
+// Reload implements Backend by telling nfsd to re-read /etc/exports.
+func (b *DarwinNFSD) Reload(ctx context.Context) error {
+	if _, err := b.Runner.Run(ctx, "sudo", "/sbin/nfsd", "update"); err != nil {
+		return fmt.Errorf("Reloading nfsd failed: %w", err)
+	}
+
+	return nil
+}
+
+// Syntax implements Backend.
+func (DarwinNFSD) Syntax() ExportSyntax { return BSDSyntax }
+
+// LinuxExportfs is the Backend for Linux, driving exportfs(8).
+type LinuxExportfs struct {
+	Runner CommandRunner
+}
+
+// NewLinuxExportfs returns the Backend used on Linux.
+func NewLinuxExportfs(runner CommandRunner) Backend {
+	return &LinuxExportfs{Runner: runner}
+}
+
+// ValidateSyntax implements Backend.
+func (LinuxExportfs) ValidateSyntax(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return fmt.Errorf("export line %q is missing a path or client", line)
+	}
+
+	for _, field := range fields[1:] {
+		if strings.HasPrefix(field, "-") {
+			return fmt.Errorf("export line %q looks like BSD syntax, not Linux exportfs", line)
+		}
+		if strings.Contains(field, "(") != strings.Contains(field, ")") {
+			return fmt.Errorf("export line %q has unbalanced client options", line)
+		}
+	}
+
+	return nil
+}
+
+// Verify implements Backend. exportfs has no offline syntax-check
+// equivalent to `nfsd checkexports`, so malformed input is instead caught
+// by ValidateSyntax and, ultimately, by Reload failing loudly.
+func (LinuxExportfs) Verify(ctx context.Context, newContents []byte) error {
+	return nil
+}
+
+// Reload implements Backend by telling exportfs to re-read /etc/exports and
+// re-export everything in it.
+func (b *LinuxExportfs) Reload(ctx context.Context) error {
+	if _, err := b.Runner.Run(ctx, "exportfs", "-ra"); err != nil {
+		return fmt.Errorf("Reloading exportfs failed: %w", err)
+	}
+
+	return nil
+}
+
+// Syntax implements Backend.
+func (LinuxExportfs) Syntax() ExportSyntax { return LinuxSyntax }
@@ -0,0 +1,64 @@
+package nfsexports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// ctxErrRunner is a CommandRunner stub that always fails with ctx's own
+// error, wrapped the way OsCommandRunner wraps a cancelled/timed-out run.
+type ctxErrRunner struct{}
+
+func (ctxErrRunner) Run(ctx context.Context, name string, arg ...string) ([]byte, error) {
+	return nil, fmt.Errorf("%w: command killed", ctx.Err())
+}
+
+func TestDarwinNFSDValidateSyntax(t *testing.T) {
+	backend := NewDarwinNFSD(NewMemMapFs(), NewOsCommandRunner())
+
+	if err := backend.ValidateSyntax("/Users 192.168.64.2 -alldirs -maproot=root"); err != nil {
+		t.Error("Rejects valid BSD syntax", err)
+	}
+
+	if err := backend.ValidateSyntax("/Users 192.168.64.2(rw,no_root_squash)"); err == nil {
+		t.Error("Accepts Linux exportfs syntax", err)
+	}
+}
+
+func TestLinuxExportfsValidateSyntax(t *testing.T) {
+	backend := NewLinuxExportfs(NewOsCommandRunner())
+
+	if err := backend.ValidateSyntax("/Users 192.168.64.2(rw,no_root_squash,sec=sys,fsid=0)"); err != nil {
+		t.Error("Rejects valid Linux exportfs syntax", err)
+	}
+
+	if err := backend.ValidateSyntax("/Users 192.168.64.2 -alldirs -maproot=root"); err == nil {
+		t.Error("Accepts BSD syntax", err)
+	}
+}
+
+func TestLinuxExportfsReloadPreservesContextError(t *testing.T) {
+	backend := NewLinuxExportfs(ctxErrRunner{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := backend.Reload(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Error("Reload did not preserve the underlying context error", err)
+	}
+}
+
+func TestDarwinNFSDReloadPreservesContextError(t *testing.T) {
+	backend := NewDarwinNFSD(NewMemMapFs(), ctxErrRunner{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := backend.Reload(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Error("Reload did not preserve the underlying context error", err)
+	}
+}
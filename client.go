@@ -0,0 +1,301 @@
+package nfsexports
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Client manages a single exports file, reading and writing it through Fs,
+// verifying/reloading it through Backend, and serializing concurrent
+// read-verify-write cycles through Locker. The package-level functions
+// (Add, Remove, Exists, List, ListAll, ReloadDaemon) are thin wrappers
+// around a Client backed by the real filesystem, os/exec, and the Backend
+// appropriate for runtime.GOOS, kept for backward compatibility.
+type Client struct {
+	Fs      Fs
+	Runner  CommandRunner
+	Backend Backend
+	Locker  Locker
+
+	// ExportsFile is the exports file this Client manages.
+	ExportsFile string
+	// LockTimeout is how long Add/Remove wait to acquire the exports lock
+	// before giving up with ErrLocked. Defaults to defaultLockTimeout.
+	LockTimeout time.Duration
+}
+
+// Option configures a Client built by NewClient or NewClientWithBackend.
+type Option func(*Client)
+
+// WithLockTimeout overrides how long Add/Remove wait to acquire the
+// exports lock before giving up with ErrLocked.
+func WithLockTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.LockTimeout = d
+	}
+}
+
+// NewClient returns a Client backed by the real filesystem and os/exec,
+// using the Backend runtime.GOOS selects (DarwinNFSD on darwin,
+// LinuxExportfs on linux). If exportsFile is empty, defaultExportsFile is
+// used. Use NewClientWithBackend to override the Backend, e.g. to manage a
+// Linux-style exports file from a non-Linux host.
+func NewClient(exportsFile string, opts ...Option) *Client {
+	fs := NewOsFs()
+	runner := NewOsCommandRunner()
+	return newClient(exportsFile, fs, runner, backendForGOOS(runtime.GOOS, fs, runner), opts)
+}
+
+// NewClientWithBackend returns a Client backed by the real filesystem and
+// os/exec, like NewClient, but using backend instead of the one
+// runtime.GOOS would select.
+func NewClientWithBackend(exportsFile string, backend Backend, opts ...Option) *Client {
+	return newClient(exportsFile, NewOsFs(), NewOsCommandRunner(), backend, opts)
+}
+
+func newClient(exportsFile string, fs Fs, runner CommandRunner, backend Backend, opts []Option) *Client {
+	if exportsFile == "" {
+		exportsFile = defaultExportsFile
+	}
+
+	c := &Client{
+		Fs:          fs,
+		Runner:      runner,
+		Backend:     backend,
+		Locker:      NewFileLocker(),
+		ExportsFile: exportsFile,
+		LockTimeout: defaultLockTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// lockFile is the sibling lockfile Locker acquires for the duration of a
+// read-verify-write cycle, e.g. "/etc/exports.lock" for "/etc/exports".
+func (c *Client) lockFile() string {
+	return c.ExportsFile + ".lock"
+}
+
+// backendForGOOS returns the Backend appropriate for goos, defaulting to
+// DarwinNFSD for anything that isn't Linux since that was this package's
+// only supported platform before Linux support was added.
+func backendForGOOS(goos string, fs Fs, runner CommandRunner) Backend {
+	switch goos {
+	case "linux":
+		return NewLinuxExportfs(runner)
+	default:
+		return NewDarwinNFSD(fs, runner)
+	}
+}
+
+// Add inserts export under identifier, serializing it according to
+// export.Syntax. Use AddRaw to add a raw, already-formatted export line
+// instead.
+func (c *Client) Add(identifier string, export Export) ([]byte, error) {
+	return c.AddContext(context.Background(), identifier, export)
+}
+
+// AddContext is Add, with ctx propagated to the Backend's external command
+// invocations so a caller can cancel a hung `nfsd checkexports`.
+func (c *Client) AddContext(ctx context.Context, identifier string, export Export) ([]byte, error) {
+	if export.Syntax == UnspecifiedSyntax {
+		export.Syntax = c.Backend.Syntax()
+	}
+	return c.AddRawContext(ctx, identifier, export.String())
+}
+
+// AddRaw inserts a raw, already-formatted export line under identifier,
+// bypassing Export. Kept for callers that built export lines themselves
+// before Export existed.
+func (c *Client) AddRaw(identifier string, export string) ([]byte, error) {
+	return c.AddRawContext(context.Background(), identifier, export)
+}
+
+// AddRawContext is AddRaw, with ctx propagated to the Backend's external
+// command invocations so a caller can cancel a hung `nfsd checkexports`.
+func (c *Client) AddRawContext(ctx context.Context, identifier string, export string) ([]byte, error) {
+	if err := c.Backend.ValidateSyntax(export); err != nil {
+		return nil, err
+	}
+
+	unlock, err := c.Locker.Lock(c.lockFile(), c.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	exports, err := c.Fs.ReadFile(c.ExportsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			exports = []byte{}
+		} else {
+			return nil, err
+		}
+	}
+
+	if containsExport(exports, identifier) {
+		return exports, nil
+	}
+
+	newExports := exports
+	if len(newExports) > 0 && !bytes.HasSuffix(exports, []byte("\n")) {
+		newExports = append(newExports, '\n')
+	}
+
+	newExports = append(newExports, []byte(exportEntry(identifier, export))...)
+
+	if err := c.Backend.Verify(ctx, newExports); err != nil {
+		return nil, err
+	}
+
+	if err := writeFileAtomic(c.Fs, c.ExportsFile, newExports, 0644); err != nil {
+		return nil, err
+	}
+
+	return newExports, nil
+}
+
+// Remove export
+func (c *Client) Remove(identifier string) ([]byte, error) {
+	return c.RemoveContext(context.Background(), identifier)
+}
+
+// RemoveContext is Remove. It accepts a context.Context for API symmetry
+// with AddContext, though removal never invokes an external command.
+func (c *Client) RemoveContext(ctx context.Context, identifier string) ([]byte, error) {
+	unlock, err := c.Locker.Lock(c.lockFile(), c.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	exports, err := c.Fs.ReadFile(c.ExportsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	beginMark := []byte(fmt.Sprintf("# BEGIN: %s", identifier))
+	endMark := []byte(fmt.Sprintf("# END: %s\n", identifier))
+
+	begin := bytes.Index(exports, beginMark)
+	end := bytes.Index(exports, endMark)
+
+	if begin == -1 || end == -1 {
+		return nil, fmt.Errorf("Couldn't not find export %s in %s", identifier, c.ExportsFile)
+	}
+
+	newExports := append(exports[:begin], exports[end+len(endMark):]...)
+	newExports = append(bytes.TrimSpace(newExports), '\n')
+
+	if err := writeFileAtomic(c.Fs, c.ExportsFile, newExports, 0644); err != nil {
+		return nil, err
+	}
+
+	return newExports, nil
+}
+
+// Exists checks the existence of a given export
+// The export must, however, have been created by this library using Add
+func (c *Client) Exists(identifier string) (bool, error) {
+	exports, err := c.Fs.ReadFile(c.ExportsFile)
+	if err != nil {
+		return false, err
+	}
+
+	beginMark := []byte(fmt.Sprintf("# BEGIN: %s", identifier))
+	endMark := []byte(fmt.Sprintf("# END: %s\n", identifier))
+
+	begin := bytes.Index(exports, beginMark)
+	end := bytes.Index(exports, endMark)
+
+	if begin == -1 || end == -1 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// List returns the list of exports *created by* nfsexports, parsed into
+// Export values. This means other exports might be present in the file but
+// won't be returned by this function. A line that fails to parse is
+// skipped.
+func (c *Client) List() (map[string]Export, error) {
+	f, err := c.Fs.Open(c.ExportsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	exports := map[string]Export{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Index(line, "# BEGIN:") != -1 {
+			if scanner.Scan() != false {
+				id := strings.TrimLeft(line, "# BEGIN:")
+				export, err := ParseExport(scanner.Text())
+				if err != nil {
+					continue
+				}
+				exports[id] = export
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// ListAll returns all nfsexports present in the exports file.
+// ListAll does not check the validity of the exports;
+// It simply returns any line present in the file that is not a comment
+func (c *Client) ListAll() ([]string, error) {
+	f, err := c.Fs.Open(c.ExportsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	exports := []string{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Index(line, "#") != -1 || len(line) == 0 {
+			continue
+		}
+		export := scanner.Text()
+		exports = append(exports, export)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// ReloadDaemon reload NFS daemon
+func (c *Client) ReloadDaemon() error {
+	return c.ReloadDaemonContext(context.Background())
+}
+
+// ReloadDaemonContext is ReloadDaemon, with ctx propagated to the Backend's
+// external command invocation so a caller can cancel a hung `nfsd update`.
+func (c *Client) ReloadDaemonContext(ctx context.Context) error {
+	return c.Backend.Reload(ctx)
+}
@@ -0,0 +1,48 @@
+package nfsexports
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// CommandRunner runs an external command and returns its combined output.
+// It exists so tests (and alternative backends) can stub out the calls to
+// nfsd/sudo without those binaries being present.
+type CommandRunner interface {
+	// Run runs name with arg, returning its combined stdout+stderr. If ctx
+	// is cancelled or times out before the command exits, the returned
+	// error wraps ctx.Err(), so callers can detect it with errors.Is(err,
+	// context.Canceled) / errors.Is(err, context.DeadlineExceeded).
+	Run(ctx context.Context, name string, arg ...string) ([]byte, error)
+}
+
+// OsCommandRunner is the default CommandRunner, backed by os/exec.
+type OsCommandRunner struct{}
+
+// NewOsCommandRunner returns the default CommandRunner, backed by os/exec.
+func NewOsCommandRunner() CommandRunner {
+	return OsCommandRunner{}
+}
+
+// Run implements CommandRunner.
+func (OsCommandRunner) Run(ctx context.Context, name string, arg ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, arg...)
+	output := &bytes.Buffer{}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	if err := cmd.Run(); err != nil {
+		// exec.Cmd.Run prefers the process's own exit error (e.g. "signal:
+		// killed") over ctx.Err(), even when ctx is what caused the kill,
+		// so check ctx.Err() directly rather than relying on it being
+		// wrapped in err.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("%w: %s", ctxErr, output)
+		}
+		return nil, fmt.Errorf("%s\n%s", err.Error(), output)
+	}
+
+	return output.Bytes(), nil
+}
@@ -0,0 +1,37 @@
+package nfsexports
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOsCommandRunnerRunReturnsCombinedOutput(t *testing.T) {
+	runner := NewOsCommandRunner()
+
+	output, err := runner.Run(context.Background(), "sh", "-c", "echo out; echo err >&2")
+	if err != nil {
+		t.Fatal("Failed running command", err)
+	}
+
+	if string(output) != "out\nerr\n" {
+		t.Error("Unexpected combined output", string(output))
+	}
+}
+
+func TestOsCommandRunnerRunDetectsContextCancellation(t *testing.T) {
+	runner := NewOsCommandRunner()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := runner.Run(ctx, "sleep", "2")
+	if err == nil {
+		t.Fatal("Expected an error from a timed-out command")
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected err to wrap context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,78 @@
+package nfsexports
+
+import (
+	"context"
+	"testing"
+)
+
+// ctxCapturingBackend records the context it was called with, so tests can
+// assert it is propagated end-to-end from Client down to Backend.
+type ctxCapturingBackend struct {
+	verifyCtx context.Context
+	reloadCtx context.Context
+	verifyErr error
+	reloadErr error
+}
+
+func (b *ctxCapturingBackend) ValidateSyntax(line string) error { return nil }
+
+func (b *ctxCapturingBackend) Verify(ctx context.Context, newContents []byte) error {
+	b.verifyCtx = ctx
+	return b.verifyErr
+}
+
+func (b *ctxCapturingBackend) Reload(ctx context.Context) error {
+	b.reloadCtx = ctx
+	return b.reloadErr
+}
+
+func (b *ctxCapturingBackend) Syntax() ExportSyntax { return BSDSyntax }
+
+func TestAddContextPropagatesContext(t *testing.T) {
+	client := testClient("")
+	backend := &ctxCapturingBackend{}
+	client.Backend = backend
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "my-id")
+
+	if _, err := client.AddContext(ctx, "my-id", Export{Syntax: BSDSyntax, Path: "/Users", Clients: []string{"192.168.64.2"}, Options: map[string]string{}}); err != nil {
+		t.Fatal("Failed adding export", err)
+	}
+
+	if backend.verifyCtx != ctx {
+		t.Error("Verify was not called with the context passed to AddContext")
+	}
+}
+
+func TestReloadDaemonContextPropagatesContext(t *testing.T) {
+	client := testClient("")
+	backend := &ctxCapturingBackend{}
+	client.Backend = backend
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "reload")
+
+	if err := client.ReloadDaemonContext(ctx); err != nil {
+		t.Fatal("Failed reloading the daemon", err)
+	}
+
+	if backend.reloadCtx != ctx {
+		t.Error("Reload was not called with the context passed to ReloadDaemonContext")
+	}
+}
+
+func TestAddUsesBackgroundContext(t *testing.T) {
+	client := testClient("")
+	backend := &ctxCapturingBackend{}
+	client.Backend = backend
+
+	export := Export{Syntax: BSDSyntax, Path: "/Users", Clients: []string{"192.168.64.2"}, Options: map[string]string{}}
+	if _, err := client.Add("my-id", export); err != nil {
+		t.Fatal("Failed adding export", err)
+	}
+
+	if backend.verifyCtx != context.Background() {
+		t.Error("Add did not fall back to context.Background()")
+	}
+}
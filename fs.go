@@ -0,0 +1,287 @@
+package nfsexports
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File is the subset of *os.File that Fs implementations hand back. It is
+// satisfied by *os.File, so OsFs needs no wrapping.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Name() string
+}
+
+// Fs abstracts the filesystem calls nfsexports needs, mirroring the subset
+// of spf13/afero.Fs used here. Callers can provide their own implementation
+// (or an afero.Fs adapter) to mount an exports file from somewhere other
+// than the real filesystem; tests use MemMapFs so they never touch
+// /etc/exports.
+type Fs interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	Open(name string) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	TempFile(dir, pattern string) (File, error)
+	Remove(name string) error
+	Rename(oldname, newname string) error
+	Chmod(name string, mode os.FileMode) error
+}
+
+// OsFs is the default Fs, backed by the real operating system.
+type OsFs struct{}
+
+// NewOsFs returns the default Fs, backed by the real operating system.
+func NewOsFs() Fs {
+	return OsFs{}
+}
+
+// ReadFile implements Fs.
+func (OsFs) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+// WriteFile implements Fs.
+func (OsFs) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(name, data, perm)
+}
+
+// Open implements Fs.
+func (OsFs) Open(name string) (File, error) {
+	return os.Open(name)
+}
+
+// Stat implements Fs.
+func (OsFs) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// TempFile implements Fs.
+func (OsFs) TempFile(dir, pattern string) (File, error) {
+	return ioutil.TempFile(dir, pattern)
+}
+
+// Remove implements Fs.
+func (OsFs) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// Rename implements Fs.
+func (OsFs) Rename(oldname, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
+// Chmod implements Fs.
+func (OsFs) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+// writeFileAtomic writes data to name by writing it to a temp file in the
+// same directory and renaming it into place, so a process that crashes
+// mid-write never leaves name half-written.
+func writeFileAtomic(fs Fs, name string, data []byte, perm os.FileMode) error {
+	tmp, err := fs.TempFile(filepath.Dir(name), ".nfsexports-tmp-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		fs.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		fs.Remove(tmp.Name())
+		return err
+	}
+
+	if err := fs.Chmod(tmp.Name(), perm); err != nil {
+		fs.Remove(tmp.Name())
+		return err
+	}
+
+	if err := fs.Rename(tmp.Name(), name); err != nil {
+		fs.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// MemMapFs is an in-memory Fs, intended for tests that exercise Add/Remove
+// without ever touching a real exports file or calling out to nfsd.
+type MemMapFs struct {
+	mu       sync.Mutex
+	files    map[string][]byte
+	tempSeen int
+}
+
+// NewMemMapFs returns an empty in-memory Fs.
+func NewMemMapFs() *MemMapFs {
+	return &MemMapFs{files: map[string][]byte{}}
+}
+
+// ReadFile implements Fs.
+func (m *MemMapFs) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	out := make([]byte, len(data))
+	copy(out, data)
+	return out, nil
+}
+
+// WriteFile implements Fs.
+func (m *MemMapFs) WriteFile(name string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]byte, len(data))
+	copy(out, data)
+	m.files[name] = out
+	return nil
+}
+
+// Open implements Fs.
+func (m *MemMapFs) Open(name string) (File, error) {
+	data, err := m.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &memFile{name: name, reader: bytes.NewReader(data)}, nil
+}
+
+// Stat implements Fs.
+func (m *MemMapFs) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+
+	return memFileInfo{name: filepath.Base(name), size: int64(len(data))}, nil
+}
+
+// TempFile implements Fs, creating a uniquely named in-memory file under
+// dir matching pattern the same way ioutil.TempFile names files on disk.
+func (m *MemMapFs) TempFile(dir, pattern string) (File, error) {
+	m.mu.Lock()
+	m.tempSeen++
+	prefix, suffix := pattern, ""
+	if i := strings.LastIndex(pattern, "*"); i != -1 {
+		prefix, suffix = pattern[:i], pattern[i+1:]
+	}
+	name := filepath.Join(dir, fmt.Sprintf("%s%d%s", prefix, m.tempSeen, suffix))
+	m.files[name] = []byte{}
+	m.mu.Unlock()
+
+	return &memFile{name: name, fs: m}, nil
+}
+
+// Remove implements Fs.
+func (m *MemMapFs) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.files[name]; !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+
+	delete(m.files, name)
+	return nil
+}
+
+// Rename implements Fs.
+func (m *MemMapFs) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.files[oldname]
+	if !ok {
+		return &os.PathError{Op: "rename", Path: oldname, Err: os.ErrNotExist}
+	}
+
+	m.files[newname] = data
+	delete(m.files, oldname)
+	return nil
+}
+
+// Chmod implements Fs. MemMapFs does not track file modes, so this is a
+// no-op once the file is confirmed to exist.
+func (m *MemMapFs) Chmod(name string, mode os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.files[name]; !ok {
+		return &os.PathError{Op: "chmod", Path: name, Err: os.ErrNotExist}
+	}
+
+	return nil
+}
+
+// memFile is the File returned by MemMapFs.Open and MemMapFs.TempFile.
+// Writes made to a file opened via TempFile are reflected back into the
+// owning MemMapFs so a subsequent ReadFile sees them, matching how
+// DarwinNFSD.Verify round-trips a temp file on the real filesystem.
+type memFile struct {
+	name   string
+	fs     *MemMapFs
+	reader *bytes.Reader
+	buf    bytes.Buffer
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.reader == nil {
+		return 0, io.EOF
+	}
+	return f.reader.Read(p)
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	n, err := f.buf.Write(p)
+	if f.fs != nil {
+		f.fs.mu.Lock()
+		f.fs.files[f.name] = append([]byte{}, f.buf.Bytes()...)
+		f.fs.mu.Unlock()
+	}
+	return n, err
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+func (f *memFile) Name() string {
+	return f.name
+}
+
+// memFileInfo is the minimal os.FileInfo returned by MemMapFs.Stat.
+type memFileInfo struct {
+	name string
+	size int64
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() os.FileMode  { return 0644 }
+func (i memFileInfo) ModTime() time.Time { return time.Time{} }
+func (i memFileInfo) IsDir() bool        { return false }
+func (i memFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,68 @@
+package nfsexports
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestMemMapFsReadWriteRoundtrip(t *testing.T) {
+	fs := NewMemMapFs()
+
+	if err := fs.WriteFile("/exports", []byte("hello"), 0644); err != nil {
+		t.Error("Failed writing file", err)
+	}
+
+	data, err := fs.ReadFile("/exports")
+	if err != nil {
+		t.Error("Failed reading file", err)
+	}
+
+	if !bytes.Equal(data, []byte("hello")) {
+		t.Error("Read back unexpected contents", string(data))
+	}
+}
+
+func TestMemMapFsReadFileNotExist(t *testing.T) {
+	fs := NewMemMapFs()
+
+	if _, err := fs.ReadFile("/missing"); !os.IsNotExist(err) {
+		t.Error("Expected a not-exist error, got", err)
+	}
+}
+
+func TestMemMapFsTempFileIsReadable(t *testing.T) {
+	fs := NewMemMapFs()
+
+	f, err := fs.TempFile("", "exports")
+	if err != nil {
+		t.Error("Failed creating temp file", err)
+	}
+
+	if _, err := f.Write([]byte("contents")); err != nil {
+		t.Error("Failed writing temp file", err)
+	}
+	f.Close()
+
+	data, err := fs.ReadFile(f.Name())
+	if err != nil {
+		t.Error("Failed reading back temp file", err)
+	}
+
+	if !bytes.Equal(data, []byte("contents")) {
+		t.Error("Read back unexpected contents", string(data))
+	}
+}
+
+func TestMemMapFsRemove(t *testing.T) {
+	fs := NewMemMapFs()
+	fs.WriteFile("/exports", []byte("hello"), 0644)
+
+	if err := fs.Remove("/exports"); err != nil {
+		t.Error("Failed removing file", err)
+	}
+
+	if _, err := fs.ReadFile("/exports"); !os.IsNotExist(err) {
+		t.Error("Expected file to be gone, got", err)
+	}
+}
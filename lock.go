@@ -0,0 +1,67 @@
+package nfsexports
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrLocked is returned by Add/Remove when the exports lockfile could not
+// be acquired within the Client's LockTimeout.
+var ErrLocked = errors.New("nfsexports: timed out waiting for the exports lock")
+
+// defaultLockTimeout is how long Add/Remove wait to acquire the exports
+// lock before giving up with ErrLocked.
+const defaultLockTimeout = 5 * time.Second
+
+// lockPollInterval is how often a blocked Lock call retries while waiting
+// for the lock to free up.
+const lockPollInterval = 10 * time.Millisecond
+
+// Locker acquires an advisory, cross-process lock on path for the duration
+// of an exports file read-verify-write cycle, so two callers (e.g. two
+// VM-provisioning tools running at once) don't race on the same exports
+// file.
+type Locker interface {
+	// Lock blocks until the lock on path is acquired or timeout elapses,
+	// in which case it returns ErrLocked. The returned unlock function
+	// releases the lock.
+	Lock(path string, timeout time.Duration) (unlock func() error, err error)
+}
+
+// FileLocker is the default Locker, using an OS advisory lock
+// (syscall.Flock) on a lockfile.
+type FileLocker struct{}
+
+// NewFileLocker returns the default Locker, backed by syscall.Flock.
+func NewFileLocker() Locker {
+	return FileLocker{}
+}
+
+// Lock implements Locker.
+func (FileLocker) Lock(path string, timeout time.Duration) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, ErrLocked
+		}
+
+		time.Sleep(lockPollInterval)
+	}
+
+	return func() error {
+		defer f.Close()
+		return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	}, nil
+}
@@ -0,0 +1,56 @@
+package nfsexports
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFileLockerLockUnlock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nfsexports-lock")
+	if err != nil {
+		t.Fatal("Failed creating test dir", err)
+	}
+	defer os.RemoveAll(dir)
+
+	locker := NewFileLocker()
+	lockPath := dir + "/exports.lock"
+
+	unlock, err := locker.Lock(lockPath, time.Second)
+	if err != nil {
+		t.Fatal("Failed acquiring the lock", err)
+	}
+
+	if err := unlock(); err != nil {
+		t.Error("Failed releasing the lock", err)
+	}
+
+	// A second, independent acquisition must succeed now that it's free.
+	unlock, err = locker.Lock(lockPath, time.Second)
+	if err != nil {
+		t.Fatal("Failed re-acquiring the freed lock", err)
+	}
+	unlock()
+}
+
+func TestFileLockerTimesOutWhenHeld(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nfsexports-lock")
+	if err != nil {
+		t.Fatal("Failed creating test dir", err)
+	}
+	defer os.RemoveAll(dir)
+
+	locker := NewFileLocker()
+	lockPath := dir + "/exports.lock"
+
+	unlock, err := locker.Lock(lockPath, time.Second)
+	if err != nil {
+		t.Fatal("Failed acquiring the lock", err)
+	}
+	defer unlock()
+
+	if _, err := locker.Lock(lockPath, 50*time.Millisecond); err != ErrLocked {
+		t.Error("Expected ErrLocked while the lock is held", err)
+	}
+}
@@ -1,13 +1,9 @@
 package nfsexports
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"os/exec"
-	"strings"
 )
 
 const (
@@ -15,177 +11,69 @@ const (
 )
 
 // Add export, if exportsFile is an empty string /etc/exports is used
-func Add(exportsFile string, identifier string, export string) ([]byte, error) {
-	if exportsFile == "" {
-		exportsFile = defaultExportsFile
-	}
-
-	exports, err := ioutil.ReadFile(exportsFile)
-
-	if err != nil {
-		if os.IsNotExist(err) {
-			exports = []byte{}
-		} else {
-			return nil, err
-		}
-	}
-
-	if containsExport(exports, identifier) {
-		return exports, nil
-	}
-
-	newExports := exports
-	if len(newExports) > 0 && !bytes.HasSuffix(exports, []byte("\n")) {
-		newExports = append(newExports, '\n')
-	}
-
-	newExports = append(newExports, []byte(exportEntry(identifier, export))...)
+func Add(exportsFile string, identifier string, export Export) ([]byte, error) {
+	return NewClient(exportsFile).Add(identifier, export)
+}
 
-	if err := verifyNewExports(newExports); err != nil {
-		return nil, err
-	}
+// AddContext is Add, with ctx propagated to the external command
+// invocations used to verify the new exports file.
+func AddContext(ctx context.Context, exportsFile string, identifier string, export Export) ([]byte, error) {
+	return NewClient(exportsFile).AddContext(ctx, identifier, export)
+}
 
-	if err := ioutil.WriteFile(exportsFile, newExports, 0644); err != nil {
-		return nil, err
-	}
+// AddRaw adds a raw, already-formatted export line, if exportsFile is an
+// empty string /etc/exports is used. Kept for callers that built export
+// lines themselves before Export existed.
+func AddRaw(exportsFile string, identifier string, export string) ([]byte, error) {
+	return NewClient(exportsFile).AddRaw(identifier, export)
+}
 
-	return newExports, nil
+// AddRawContext is AddRaw, with ctx propagated to the external command
+// invocations used to verify the new exports file.
+func AddRawContext(ctx context.Context, exportsFile string, identifier string, export string) ([]byte, error) {
+	return NewClient(exportsFile).AddRawContext(ctx, identifier, export)
 }
 
 // Remove export, if exportsFile is an empty string /etc/exports is used
 func Remove(exportsFile string, identifier string) ([]byte, error) {
-	if exportsFile == "" {
-		exportsFile = defaultExportsFile
-	}
-
-	exports, err := ioutil.ReadFile(exportsFile)
-	if err != nil {
-		return nil, err
-	}
-
-	beginMark := []byte(fmt.Sprintf("# BEGIN: %s", identifier))
-	endMark := []byte(fmt.Sprintf("# END: %s\n", identifier))
-
-	begin := bytes.Index(exports, beginMark)
-	end := bytes.Index(exports, endMark)
-
-	if begin == -1 || end == -1 {
-		return nil, fmt.Errorf("Couldn't not find export %s in %s", identifier, exportsFile)
-	}
-
-	newExports := append(exports[:begin], exports[end+len(endMark):]...)
-	newExports = append(bytes.TrimSpace(newExports), '\n')
-
-	if err := ioutil.WriteFile(exportsFile, newExports, 0644); err != nil {
-		return nil, err
-	}
+	return NewClient(exportsFile).Remove(identifier)
+}
 
-	return newExports, nil
+// RemoveContext is Remove, accepting a context.Context for API symmetry
+// with AddContext.
+func RemoveContext(ctx context.Context, exportsFile string, identifier string) ([]byte, error) {
+	return NewClient(exportsFile).RemoveContext(ctx, identifier)
 }
 
 // Exists checks the existence of a given export
 // The export must, however, have been created by this library using Add
 func Exists(exportsFile string, identifier string) (bool, error) {
-	if exportsFile == "" {
-		exportsFile = defaultExportsFile
-	}
-
-	exports, err := ioutil.ReadFile(exportsFile)
-	if err != nil {
-		return false, err
-	}
-
-	beginMark := []byte(fmt.Sprintf("# BEGIN: %s", identifier))
-	endMark := []byte(fmt.Sprintf("# END: %s\n", identifier))
-
-	begin := bytes.Index(exports, beginMark)
-	end := bytes.Index(exports, endMark)
-
-	if begin == -1 || end == -1 {
-		return false, nil
-	}
-
-	return true, nil
+	return NewClient(exportsFile).Exists(identifier)
 }
 
-// List returns the list of exports *created by* nfsexports
-// This means other exports might be present in the file but won't
-// be returned by this function
-func List(exportsFile string) (map[string]string, error) {
-	if exportsFile == "" {
-		exportsFile = defaultExportsFile
-	}
-
-	f, err := os.Open(exportsFile)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-
-	exports := map[string]string{}
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Index(line, "# BEGIN:") != -1 {
-			if scanner.Scan() != false {
-				id := strings.TrimLeft(line, "# BEGIN:")
-				export := scanner.Text()
-				exports[id] = export
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	return exports, nil
+// List returns the list of exports *created by* nfsexports, parsed into
+// Export values. This means other exports might be present in the file but
+// won't be returned by this function
+func List(exportsFile string) (map[string]Export, error) {
+	return NewClient(exportsFile).List()
 }
 
 // ListAll returns all nfsexports present in the exports file.
 // ListAll does not check the validity of the exports;
 // It simply returns any line present in the file that is not a comment
 func ListAll(exportsFile string) ([]string, error) {
-	if exportsFile == "" {
-		exportsFile = defaultExportsFile
-	}
-
-	f, err := os.Open(exportsFile)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	scanner := bufio.NewScanner(f)
-
-	exports := []string{}
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Index(line, "#") != -1 || len(line) == 0 {
-			continue
-		}
-		export := scanner.Text()
-		exports = append(exports, export)
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	return exports, nil
+	return NewClient(exportsFile).ListAll()
 }
 
 // ReloadDaemon reload NFS daemon
 func ReloadDaemon() error {
-	cmd := exec.Command("sudo", "/sbin/nfsd", "update")
-	cmd.Stderr = &bytes.Buffer{}
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("Reloading nfsd failed: %s\n%s", err.Error(), cmd.Stderr)
-	}
+	return NewClient("").ReloadDaemon()
+}
 
-	return nil
+// ReloadDaemonContext is ReloadDaemon, with ctx propagated to the external
+// command invocation used to reload the NFS daemon.
+func ReloadDaemonContext(ctx context.Context) error {
+	return NewClient("").ReloadDaemonContext(ctx)
 }
 
 func containsExport(exports []byte, identifier string) bool {
@@ -195,25 +83,3 @@ func containsExport(exports []byte, identifier string) bool {
 func exportEntry(identifier string, export string) string {
 	return fmt.Sprintf("# BEGIN: %s\n%s\n# END: %s\n", identifier, export, identifier)
 }
-
-func verifyNewExports(newExports []byte) error {
-	tmpFile, err := ioutil.TempFile("", "exports")
-	if err != nil {
-		return err
-	}
-	defer tmpFile.Close()
-
-	if _, err := tmpFile.Write(newExports); err != nil {
-		return err
-	}
-	tmpFile.Close()
-
-	cmd := exec.Command("/sbin/nfsd", "-F", tmpFile.Name(), "checkexports")
-	cmd.Stderr = &bytes.Buffer{}
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("Export verification failed:\n%s\n%s", cmd.Stderr, err.Error())
-	}
-
-	return nil
-}
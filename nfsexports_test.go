@@ -2,18 +2,55 @@ package nfsexports
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"testing"
+	"time"
 )
 
-func TestAddWithValid(t *testing.T) {
-	exportsFile, err := exportsFile(`/Users 192.168.64.1 -alldirs -maproot=root`)
-	if err != nil {
-		t.Error("Failed creating test exports file", err)
+const testExportsFile = "/etc/exports"
+
+// fakeBackend stubs out Backend so tests never shell out to a real nfsd or
+// exportfs binary.
+type fakeBackend struct {
+	verifyErr error
+}
+
+func (b *fakeBackend) ValidateSyntax(line string) error { return nil }
+func (b *fakeBackend) Verify(ctx context.Context, newContents []byte) error {
+	return b.verifyErr
+}
+func (b *fakeBackend) Reload(ctx context.Context) error { return nil }
+func (b *fakeBackend) Syntax() ExportSyntax             { return BSDSyntax }
+
+// fakeLocker is a no-op Locker so tests never touch a real lockfile.
+type fakeLocker struct{}
+
+func (fakeLocker) Lock(path string, timeout time.Duration) (func() error, error) {
+	return func() error { return nil }, nil
+}
+
+func testClient(content string) *Client {
+	fs := NewMemMapFs()
+	if content != "" {
+		fs.WriteFile(testExportsFile, []byte(content), 0644)
 	}
 
-	result, err := Add(exportsFile, "my-id", "/Users 192.168.64.2 -alldirs -maproot=root")
+	return &Client{
+		Fs:          fs,
+		Runner:      NewOsCommandRunner(),
+		Backend:     &fakeBackend{},
+		Locker:      fakeLocker{},
+		ExportsFile: testExportsFile,
+		LockTimeout: defaultLockTimeout,
+	}
+}
+
+func TestAddWithValid(t *testing.T) {
+	client := testClient(`/Users 192.168.64.1 -alldirs -maproot=root`)
+
+	result, err := client.AddRaw("my-id", "/Users 192.168.64.2 -alldirs -maproot=root")
 	if err != nil {
 		t.Error("Accepts additions resulting in a valid exports file", err)
 	}
@@ -28,16 +65,13 @@ func TestAddWithValid(t *testing.T) {
 }
 
 func TestAddWithExistingIdentifier(t *testing.T) {
-	exportsFile, err := exportsFile(`/Users 192.168.64.1 -alldirs -maproot=root
+	client := testClient(`/Users 192.168.64.1 -alldirs -maproot=root
 # BEGIN: my-id
 /Users 192.168.64.2 -alldirs -maproot=root
 # END: my-id
 `)
-	if err != nil {
-		t.Error("Failed creating test exports file", err)
-	}
 
-	result, err := Add(exportsFile, "my-id", "/Users 192.168.64.2 -alldirs -maproot=root")
+	result, err := client.AddRaw("my-id", "/Users 192.168.64.2 -alldirs -maproot=root")
 	if err != nil {
 		t.Error("Accepts additions resulting in a valid exports file", err)
 	}
@@ -52,13 +86,11 @@ func TestAddWithExistingIdentifier(t *testing.T) {
 }
 
 func TestAddWithInvalid(t *testing.T) {
-	exportsFile, err := exportsFile(`/Users/my-user 192.168.64.1 -alldirs -maproot=root
+	client := testClient(`/Users/my-user 192.168.64.1 -alldirs -maproot=root
 `)
-	if err != nil {
-		t.Error("Failed creating test exports file", err)
-	}
+	client.Backend = &fakeBackend{verifyErr: errors.New("export_check: bad path")}
 
-	result, err := Add(exportsFile, "my-id", "/Users 192.168.64.2 -alldirs -maproot=root")
+	result, err := client.AddRaw("my-id", "/Users 192.168.64.2 -alldirs -maproot=root")
 	if err == nil {
 		t.Error("Rejects additions resulting in an invalid exports file", err)
 	}
@@ -69,16 +101,13 @@ func TestAddWithInvalid(t *testing.T) {
 }
 
 func TestCheckExistsWithValid(t *testing.T) {
-	exportsFile, err := exportsFile(`/Users 192.168.64.1 -alldirs -maproot=root
+	client := testClient(`/Users 192.168.64.1 -alldirs -maproot=root
 # BEGIN: my-id
 /Users 192.168.64.2 -alldirs -maproot=root
 # END: my-id
 `)
-	if err != nil {
-		t.Error("Failed creating test exports file", err)
-	}
 
-	result, err := Exists(exportsFile, "my-id")
+	result, err := client.Exists("my-id")
 	if err != nil {
 		t.Error("Checking existence of valid exports fails", err)
 	} else if result == false {
@@ -87,16 +116,13 @@ func TestCheckExistsWithValid(t *testing.T) {
 }
 
 func TestCheckExistsWithInvalid(t *testing.T) {
-	exportsFile, err := exportsFile(`/Users 192.168.64.1 -alldirs -maproot=root
+	client := testClient(`/Users 192.168.64.1 -alldirs -maproot=root
 # BEGIN: my-id
 /Users 192.168.64.2 -alldirs -maproot=root
 # END: my-id
 `)
-	if err != nil {
-		t.Error("Failed creating test exports file", err)
-	}
 
-	result, err := Exists(exportsFile, "my-invalid-id")
+	result, err := client.Exists("my-invalid-id")
 	if err != nil {
 		t.Error("Checking existence of invalid exports fails", err)
 	} else if result == true {
@@ -118,22 +144,22 @@ func TestList(t *testing.T) {
 	}
 	contents += "/Users 192.168.64.6 -alldirs -maproot=root"
 
-	exportsFile, err := exportsFile(contents)
+	client := testClient(contents)
+
+	exports, err := client.List()
 	if err != nil {
-		t.Error("Failed creating test exports file", err)
+		t.Error("Failed listing exports", err)
 	}
 
-	exports, err := List(exportsFile)
-
 	for id, export := range exports {
-		if expected[id] != export {
-			t.Error("nfsexport id", id, "not matching", export)
+		if expected[id] != export.String() {
+			t.Error("nfsexport id", id, "not matching", export.String())
 		}
 	}
 }
 
 func TestListAll(t *testing.T) {
-	exportsFile, err := exportsFile(`/Users 192.168.64.1 -alldirs -maproot=root
+	client := testClient(`/Users 192.168.64.1 -alldirs -maproot=root
 # BEGIN: my-id
 /Users 192.168.64.2 -alldirs -maproot=root
 # END: my-id
@@ -146,9 +172,6 @@ func TestListAll(t *testing.T) {
 
 /Users 192.168.64.5 -alldirs -maproot=root
 `)
-	if err != nil {
-		t.Error("Failed creating test exports file", err)
-	}
 
 	expected := map[string]bool{
 		"/Users 192.168.64.1 -alldirs -maproot=root": true,
@@ -158,7 +181,10 @@ func TestListAll(t *testing.T) {
 		"/Users 192.168.64.5 -alldirs -maproot=root": true,
 	}
 
-	exports, err := ListAll(exportsFile)
+	exports, err := client.ListAll()
+	if err != nil {
+		t.Error("Failed listing exports", err)
+	}
 
 	if len(exports) < len(expected) {
 		t.Error("Missing NFS export")
@@ -173,13 +199,9 @@ func TestListAll(t *testing.T) {
 }
 
 func TestAddNewFile(t *testing.T) {
-	tempDir, err := ioutil.TempDir("", "nfsexports")
-	if err != nil {
-		t.Error("Failed creating test exports dir", err)
-	}
+	client := testClient("")
 
-	exportsFile := fmt.Sprintf("%s/exports", tempDir)
-	result, err := Add(exportsFile, "my-id", "/Users 192.168.64.2 -alldirs -maproot=root")
+	result, err := client.AddRaw("my-id", "/Users 192.168.64.2 -alldirs -maproot=root")
 	if err != nil {
 		t.Error("Accepts additions to an new file", err)
 	}
@@ -193,13 +215,10 @@ func TestAddNewFile(t *testing.T) {
 }
 
 func TestRemoveNotExisting(t *testing.T) {
-	exportsFile, err := exportsFile(`/Users/my-user 192.168.64.1 -alldirs -maproot=root
+	client := testClient(`/Users/my-user 192.168.64.1 -alldirs -maproot=root
 `)
-	if err != nil {
-		t.Error("Failed creating test exports file", err)
-	}
 
-	result, err := Remove(exportsFile, "my-id")
+	result, err := client.Remove("my-id")
 	if err == nil {
 		t.Error("Errors when removing an unknown identifier", err)
 	}
@@ -210,16 +229,13 @@ func TestRemoveNotExisting(t *testing.T) {
 }
 
 func TestRemoveExisting(t *testing.T) {
-	exportsFile, err := exportsFile(`/Users 192.168.64.1 -alldirs -maproot=root
+	client := testClient(`/Users 192.168.64.1 -alldirs -maproot=root
 # BEGIN: my-id
 /Users 192.168.64.2 -alldirs -maproot=root
 # END: my-id
 `)
-	if err != nil {
-		t.Error("Failed creating test exports file", err)
-	}
 
-	result, err := Remove(exportsFile, "my-id")
+	result, err := client.Remove("my-id")
 	if err != nil {
 		t.Error("Removes an known indentifier without error", err)
 	}
@@ -231,16 +247,13 @@ func TestRemoveExisting(t *testing.T) {
 }
 
 func TestRemoveLast(t *testing.T) {
-	exportsFile, err := exportsFile(`/Users 192.168.64.1 -alldirs -maproot=root
+	client := testClient(`/Users 192.168.64.1 -alldirs -maproot=root
 # BEGIN: my-id
 /Users 192.168.64.2 -alldirs -maproot=root
 # END: my-id
 `)
-	if err != nil {
-		t.Error("Failed creating test exports file", err)
-	}
 
-	result, err := Remove(exportsFile, "my-id")
+	result, err := client.Remove("my-id")
 	if err != nil {
 		t.Error("Removes an known indentifier without error", err)
 	}
@@ -252,23 +265,10 @@ func TestRemoveLast(t *testing.T) {
 }
 
 func TestReloadDaemon(t *testing.T) {
-	err := ReloadDaemon()
-	if err != nil {
-		t.Error("Allows to reload nfsd", err)
-	}
-}
+	client := testClient("")
 
-func exportsFile(content string) (string, error) {
-	tmpFile, err := ioutil.TempFile("", "exports-test")
+	err := client.ReloadDaemon()
 	if err != nil {
-		return "", err
-	}
-	defer tmpFile.Close()
-
-	if _, err := tmpFile.Write([]byte(content)); err != nil {
-		return "", err
+		t.Error("Allows to reload nfsd", err)
 	}
-	tmpFile.Close()
-
-	return tmpFile.Name(), nil
 }
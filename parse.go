@@ -0,0 +1,270 @@
+package nfsexports
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ExportSyntax selects which exports-file dialect an Export is parsed from
+// or serialized as.
+type ExportSyntax int
+
+const (
+	// UnspecifiedSyntax is the zero value of ExportSyntax. Client.Add and
+	// Transaction.Add resolve it to the owning Client's Backend.Syntax()
+	// before serializing; called directly, Export.String defaults it to
+	// BSDSyntax.
+	UnspecifiedSyntax ExportSyntax = iota
+	// BSDSyntax is the macOS /etc/exports dialect: "/path client -opt -opt=val".
+	BSDSyntax
+	// LinuxSyntax is the Linux /etc/exports dialect: "/path client(opt,opt)".
+	LinuxSyntax
+)
+
+// Export is a single, structured NFS export entry. It can be built
+// programmatically - adding a client subnet, toggling -alldirs, changing
+// fsid - instead of doing string surgery on a raw export line, and
+// round-trips through ParseExport/String without losing information.
+type Export struct {
+	// Syntax selects the dialect String() serializes this Export as. Leave
+	// it as UnspecifiedSyntax when building an Export for Client.Add or
+	// Transaction.Add; they fill it in from the Backend.
+	Syntax ExportSyntax
+	// Path is the exported directory, e.g. "/Users".
+	Path string
+
+	// Clients are the hosts/networks allowed to mount Path, for
+	// BSD-syntax exports, where every client on the line shares the same
+	// option set. Linux-syntax exports use LinuxClients instead, since
+	// Linux allows options to differ per client.
+	Clients []string
+	// Options holds any BSD option not covered by a dedicated field
+	// below, keyed by option name. Flag-only options (no "=value") map to
+	// the empty string. BSD-syntax only.
+	Options map[string]string
+
+	// AllDirs is BSD's -alldirs: clients may mount any subdirectory of
+	// Path, not just Path itself. BSD-syntax only.
+	AllDirs bool
+	// MapRoot is BSD's -maproot=<user>. BSD-syntax only.
+	MapRoot string
+	// NetworkMask is BSD's -mask=<mask>, paired with a -network=<net>
+	// client entry. BSD-syntax only.
+	NetworkMask string
+
+	// LinuxClients holds each client and its own option set, for
+	// Linux-syntax exports.
+	LinuxClients []LinuxExportClient
+}
+
+// LinuxExportClient is a single client and its own option set within a
+// Linux-syntax export line, e.g. the "client(rw,no_root_squash)" in
+// "/srv client(rw,no_root_squash)". Unlike BSD, Linux allows clients on the
+// same line to have different options, so these can't be hoisted onto
+// shared Export fields without losing information.
+type LinuxExportClient struct {
+	// Host is the client hostname/network, e.g. "192.168.64.0/24".
+	Host string
+	// NoRootSquash is this client's no_root_squash option: its root user is
+	// not mapped to the anonymous user.
+	NoRootSquash bool
+	// Sec is this client's sec=<mode> option (e.g. "sys", "krb5"), if set.
+	Sec string
+	// FSID is this client's fsid=<id> option, if set.
+	FSID string
+	// Options holds any option not covered by a dedicated field above,
+	// keyed by option name. Flag-only options (no "=value") map to the
+	// empty string.
+	Options map[string]string
+}
+
+// ParseExport parses a single raw export line, auto-detecting BSD or Linux
+// syntax from whether any field uses parenthesized client options.
+func ParseExport(line string) (Export, error) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return Export{}, fmt.Errorf("export line is empty")
+	}
+
+	if strings.ContainsAny(trimmed, "()") {
+		return parseLinuxExport(trimmed)
+	}
+
+	return parseBSDExport(trimmed)
+}
+
+func parseBSDExport(line string) (Export, error) {
+	fields := strings.Fields(line)
+
+	export := Export{Syntax: BSDSyntax, Path: fields[0], Options: map[string]string{}}
+
+	i := 1
+	for ; i < len(fields); i++ {
+		if strings.HasPrefix(fields[i], "-") {
+			break
+		}
+		export.Clients = append(export.Clients, fields[i])
+	}
+
+	for ; i < len(fields); i++ {
+		name, value := splitOption(strings.TrimPrefix(fields[i], "-"))
+
+		switch name {
+		case "alldirs":
+			export.AllDirs = true
+		case "maproot":
+			export.MapRoot = value
+		case "mask":
+			export.NetworkMask = value
+		default:
+			export.Options[name] = value
+		}
+	}
+
+	return export, nil
+}
+
+func parseLinuxExport(line string) (Export, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return Export{}, fmt.Errorf("export line %q is missing a path or client", line)
+	}
+
+	export := Export{Syntax: LinuxSyntax, Path: fields[0]}
+
+	for _, field := range fields[1:] {
+		host, opts, err := splitLinuxClient(field)
+		if err != nil {
+			return Export{}, fmt.Errorf("export line %q: %s", line, err.Error())
+		}
+
+		client := LinuxExportClient{Host: host, Options: map[string]string{}}
+		for _, opt := range opts {
+			name, value := splitOption(opt)
+
+			switch name {
+			case "no_root_squash":
+				client.NoRootSquash = true
+			case "root_squash":
+				client.NoRootSquash = false
+			case "sec":
+				client.Sec = value
+			case "fsid":
+				client.FSID = value
+			default:
+				client.Options[name] = value
+			}
+		}
+
+		export.LinuxClients = append(export.LinuxClients, client)
+	}
+
+	return export, nil
+}
+
+// splitLinuxClient splits a single "client(opt,opt)" field into the client
+// name and its options. The parenthesized part is optional.
+func splitLinuxClient(field string) (client string, opts []string, err error) {
+	open := strings.IndexByte(field, '(')
+	if open == -1 {
+		return field, nil, nil
+	}
+
+	if !strings.HasSuffix(field, ")") {
+		return "", nil, fmt.Errorf("client %q has unbalanced options", field)
+	}
+
+	client = field[:open]
+	raw := field[open+1 : len(field)-1]
+	if raw == "" {
+		return client, nil, nil
+	}
+
+	return client, strings.Split(raw, ","), nil
+}
+
+// splitOption splits "name=value" into its parts; options without a value
+// return an empty value.
+func splitOption(opt string) (name string, value string) {
+	if i := strings.Index(opt, "="); i != -1 {
+		return opt[:i], opt[i+1:]
+	}
+	return opt, ""
+}
+
+// String serializes the Export back into a raw export line, in the dialect
+// Syntax selects. UnspecifiedSyntax defaults to BSDSyntax; Client.Add and
+// Transaction.Add resolve it to the Backend's dialect first, so this
+// default is only reached when String is called directly.
+func (e Export) String() string {
+	if e.Syntax == LinuxSyntax {
+		return e.linuxString()
+	}
+	return e.bsdString()
+}
+
+func (e Export) bsdString() string {
+	parts := append([]string{e.Path}, e.Clients...)
+
+	if e.AllDirs {
+		parts = append(parts, "-alldirs")
+	}
+	if e.MapRoot != "" {
+		parts = append(parts, "-maproot="+e.MapRoot)
+	}
+	if e.NetworkMask != "" {
+		parts = append(parts, "-mask="+e.NetworkMask)
+	}
+	parts = append(parts, formatOptions(e.Options, "-")...)
+
+	return strings.Join(parts, " ")
+}
+
+func (e Export) linuxString() string {
+	parts := []string{e.Path}
+
+	for _, client := range e.LinuxClients {
+		opts := []string{}
+		if client.NoRootSquash {
+			opts = append(opts, "no_root_squash")
+		}
+		if client.Sec != "" {
+			opts = append(opts, "sec="+client.Sec)
+		}
+		if client.FSID != "" {
+			opts = append(opts, "fsid="+client.FSID)
+		}
+		opts = append(opts, formatOptions(client.Options, "")...)
+		sort.Strings(opts)
+
+		if len(opts) == 0 {
+			parts = append(parts, client.Host)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s(%s)", client.Host, strings.Join(opts, ",")))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// formatOptions renders a sorted, deterministic "name" or "name=value" list
+// for each entry in options, each prefixed with prefix.
+func formatOptions(options map[string]string, prefix string) []string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	formatted := make([]string, 0, len(names))
+	for _, name := range names {
+		if value := options[name]; value != "" {
+			formatted = append(formatted, fmt.Sprintf("%s%s=%s", prefix, name, value))
+		} else {
+			formatted = append(formatted, prefix+name)
+		}
+	}
+
+	return formatted
+}
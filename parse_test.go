@@ -0,0 +1,155 @@
+package nfsexports
+
+import "testing"
+
+func TestParseExportBSD(t *testing.T) {
+	export, err := ParseExport("/Users 192.168.64.1 192.168.64.2 -alldirs -maproot=root -mapall=nobody")
+	if err != nil {
+		t.Fatal("Failed parsing valid BSD export", err)
+	}
+
+	if export.Syntax != BSDSyntax {
+		t.Error("Expected BSDSyntax", export.Syntax)
+	}
+	if export.Path != "/Users" {
+		t.Error("Unexpected path", export.Path)
+	}
+	if len(export.Clients) != 2 || export.Clients[0] != "192.168.64.1" || export.Clients[1] != "192.168.64.2" {
+		t.Error("Unexpected clients", export.Clients)
+	}
+	if !export.AllDirs {
+		t.Error("Expected AllDirs to be set")
+	}
+	if export.MapRoot != "root" {
+		t.Error("Unexpected MapRoot", export.MapRoot)
+	}
+	if export.Options["mapall"] != "nobody" {
+		t.Error("Unexpected mapall option", export.Options)
+	}
+}
+
+func TestParseExportBSDRoundtrip(t *testing.T) {
+	line := "/Users 192.168.64.2 -alldirs -maproot=root"
+
+	export, err := ParseExport(line)
+	if err != nil {
+		t.Fatal("Failed parsing valid BSD export", err)
+	}
+
+	if export.String() != line {
+		t.Error("Roundtrip changed the export line", export.String())
+	}
+}
+
+func TestParseExportLinux(t *testing.T) {
+	export, err := ParseExport("/srv/nfs 192.168.64.0/24(rw,no_root_squash,sec=sys,fsid=0)")
+	if err != nil {
+		t.Fatal("Failed parsing valid Linux export", err)
+	}
+
+	if export.Syntax != LinuxSyntax {
+		t.Error("Expected LinuxSyntax", export.Syntax)
+	}
+	if export.Path != "/srv/nfs" {
+		t.Error("Unexpected path", export.Path)
+	}
+	if len(export.LinuxClients) != 1 || export.LinuxClients[0].Host != "192.168.64.0/24" {
+		t.Error("Unexpected clients", export.LinuxClients)
+	}
+
+	client := export.LinuxClients[0]
+	if !client.NoRootSquash {
+		t.Error("Unexpected NoRootSquash", client.NoRootSquash)
+	}
+	if client.Sec != "sys" {
+		t.Error("Unexpected Sec", client.Sec)
+	}
+	if client.FSID != "0" {
+		t.Error("Unexpected FSID", client.FSID)
+	}
+	if _, ok := client.Options["rw"]; !ok {
+		t.Error("Unexpected rw option", client.Options)
+	}
+}
+
+func TestParseExportLinuxRoundtrip(t *testing.T) {
+	line := "/srv/nfs 192.168.64.0/24(fsid=0,no_root_squash,rw,sec=sys)"
+
+	export, err := ParseExport(line)
+	if err != nil {
+		t.Fatal("Failed parsing valid Linux export", err)
+	}
+
+	if export.String() != line {
+		t.Error("Roundtrip changed the export line", export.String())
+	}
+}
+
+func TestParseExportLinuxMultiClientRoundtrip(t *testing.T) {
+	line := "/srv client-a(ro) client-b(rw)"
+
+	export, err := ParseExport(line)
+	if err != nil {
+		t.Fatal("Failed parsing valid Linux export", err)
+	}
+
+	if len(export.LinuxClients) != 2 {
+		t.Fatal("Unexpected clients", export.LinuxClients)
+	}
+
+	if _, ok := export.LinuxClients[0].Options["ro"]; !ok {
+		t.Error("client-a missing its ro option", export.LinuxClients[0])
+	}
+	if _, ok := export.LinuxClients[0].Options["rw"]; ok {
+		t.Error("client-a picked up client-b's rw option", export.LinuxClients[0])
+	}
+	if _, ok := export.LinuxClients[1].Options["rw"]; !ok {
+		t.Error("client-b missing its rw option", export.LinuxClients[1])
+	}
+	if _, ok := export.LinuxClients[1].Options["ro"]; ok {
+		t.Error("client-b picked up client-a's ro option", export.LinuxClients[1])
+	}
+
+	if export.String() != line {
+		t.Error("Roundtrip changed the export line, each client's options should stay its own", export.String())
+	}
+}
+
+func TestParseExportEmpty(t *testing.T) {
+	if _, err := ParseExport(""); err == nil {
+		t.Error("Expected an error for an empty export line")
+	}
+}
+
+func TestParseExportLinuxUnbalancedOptions(t *testing.T) {
+	if _, err := ParseExport("/srv/nfs 192.168.64.0/24(rw"); err == nil {
+		t.Error("Expected an error for unbalanced client options")
+	}
+}
+
+func TestAddWithExport(t *testing.T) {
+	client := testClient(`/Users 192.168.64.1 -alldirs -maproot=root`)
+
+	export := Export{
+		Syntax:  BSDSyntax,
+		Path:    "/Users",
+		Clients: []string{"192.168.64.2"},
+		Options: map[string]string{},
+		AllDirs: true,
+		MapRoot: "root",
+	}
+
+	result, err := client.Add("my-id", export)
+	if err != nil {
+		t.Error("Accepts additions resulting in a valid exports file", err)
+	}
+
+	expected := `/Users 192.168.64.1 -alldirs -maproot=root
+# BEGIN: my-id
+/Users 192.168.64.2 -alldirs -maproot=root
+# END: my-id
+`
+	if string(result) != expected {
+		t.Error("Generates an expected result", string(result))
+	}
+}
@@ -0,0 +1,185 @@
+package nfsexports
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// errTransactionClosed is returned by any Transaction method called after
+// Commit or Rollback.
+var errTransactionClosed = errors.New("nfsexports: transaction already committed or rolled back")
+
+// Transaction buffers a sequence of Add/Remove/Replace operations in
+// memory and applies them as a single verify-then-write-then-reload on
+// Commit, instead of Client's verify-and-write per call. This avoids the
+// O(N) cost of installing many exports one at a time (e.g. at boot) and
+// guarantees the on-disk exports file is either left untouched or fully
+// updated, never half-updated.
+//
+// A Transaction holds the exports lock for its entire lifetime, so always
+// call Commit or Rollback.
+type Transaction struct {
+	client   *Client
+	unlock   func() error
+	snapshot []byte
+	buffer   []byte
+	closed   bool
+}
+
+// Begin starts a Transaction against exportsFile (or defaultExportsFile if
+// exportsFile is empty), backed by the real filesystem, os/exec, and the
+// Backend runtime.GOOS selects.
+func Begin(exportsFile string) (*Transaction, error) {
+	return NewClient(exportsFile).Begin()
+}
+
+// Begin starts a Transaction against c's exports file.
+func (c *Client) Begin() (*Transaction, error) {
+	unlock, err := c.Locker.Lock(c.lockFile(), c.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	exports, err := c.Fs.ReadFile(c.ExportsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			exports = []byte{}
+		} else {
+			unlock()
+			return nil, err
+		}
+	}
+
+	return &Transaction{
+		client:   c,
+		unlock:   unlock,
+		snapshot: append([]byte{}, exports...),
+		buffer:   append([]byte{}, exports...),
+	}, nil
+}
+
+// Add buffers inserting export under identifier, serializing it according
+// to export.Syntax (resolved from the Client's Backend if left as
+// UnspecifiedSyntax). Like Client.Add, it is a no-op if identifier is
+// already present in the buffered result.
+func (t *Transaction) Add(identifier string, export Export) error {
+	if export.Syntax == UnspecifiedSyntax {
+		export.Syntax = t.client.Backend.Syntax()
+	}
+	return t.AddRaw(identifier, export.String())
+}
+
+// AddRaw buffers inserting a raw, already-formatted export line under
+// identifier, bypassing Export.
+func (t *Transaction) AddRaw(identifier string, export string) error {
+	if t.closed {
+		return errTransactionClosed
+	}
+
+	if err := t.client.Backend.ValidateSyntax(export); err != nil {
+		return err
+	}
+
+	if containsExport(t.buffer, identifier) {
+		return nil
+	}
+
+	if len(t.buffer) > 0 && !bytes.HasSuffix(t.buffer, []byte("\n")) {
+		t.buffer = append(t.buffer, '\n')
+	}
+	t.buffer = append(t.buffer, []byte(exportEntry(identifier, export))...)
+
+	return nil
+}
+
+// Remove buffers removing identifier from the result.
+func (t *Transaction) Remove(identifier string) error {
+	if t.closed {
+		return errTransactionClosed
+	}
+
+	beginMark := []byte(fmt.Sprintf("# BEGIN: %s", identifier))
+	endMark := []byte(fmt.Sprintf("# END: %s\n", identifier))
+
+	begin := bytes.Index(t.buffer, beginMark)
+	end := bytes.Index(t.buffer, endMark)
+
+	if begin == -1 || end == -1 {
+		return fmt.Errorf("Couldn't not find export %s in %s", identifier, t.client.ExportsFile)
+	}
+
+	t.buffer = append(t.buffer[:begin], t.buffer[end+len(endMark):]...)
+	t.buffer = append(bytes.TrimSpace(t.buffer), '\n')
+
+	return nil
+}
+
+// Replace buffers removing identifier, if present, and re-adding it as
+// export, e.g. to change an existing export's options in place.
+func (t *Transaction) Replace(identifier string, export Export) error {
+	if t.closed {
+		return errTransactionClosed
+	}
+
+	if containsExport(t.buffer, identifier) {
+		if err := t.Remove(identifier); err != nil {
+			return err
+		}
+	}
+
+	return t.Add(identifier, export)
+}
+
+// Commit verifies the buffered result once, then writes and reloads it. If
+// verification or the write fails, the on-disk exports file is left
+// untouched. If Reload fails after a successful write, Commit restores the
+// contents captured at Begin before returning the error.
+func (t *Transaction) Commit() error {
+	return t.CommitContext(context.Background())
+}
+
+// CommitContext is Commit, with ctx propagated to the Backend's external
+// command invocations.
+func (t *Transaction) CommitContext(ctx context.Context) error {
+	if t.closed {
+		return errTransactionClosed
+	}
+	defer t.close()
+
+	if err := t.client.Backend.Verify(ctx, t.buffer); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(t.client.Fs, t.client.ExportsFile, t.buffer, 0644); err != nil {
+		return err
+	}
+
+	if err := t.client.Backend.Reload(ctx); err != nil {
+		if restoreErr := writeFileAtomic(t.client.Fs, t.client.ExportsFile, t.snapshot, 0644); restoreErr != nil {
+			return fmt.Errorf("%s (additionally failed to restore the previous exports file: %s)", err.Error(), restoreErr.Error())
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Rollback discards the buffered operations without touching the on-disk
+// exports file.
+func (t *Transaction) Rollback() error {
+	if t.closed {
+		return errTransactionClosed
+	}
+	t.close()
+	return nil
+}
+
+func (t *Transaction) close() {
+	if !t.closed {
+		t.closed = true
+		t.unlock()
+	}
+}
@@ -0,0 +1,160 @@
+package nfsexports
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTransactionCommit(t *testing.T) {
+	client := testClient(`/Users 192.168.64.1 -alldirs -maproot=root`)
+
+	tx, err := client.Begin()
+	if err != nil {
+		t.Fatal("Failed beginning transaction", err)
+	}
+
+	if err := tx.AddRaw("my-id", "/Users 192.168.64.2 -alldirs -maproot=root"); err != nil {
+		t.Fatal("Failed buffering add", err)
+	}
+	if err := tx.AddRaw("my-id2", "/Users 192.168.64.3 -alldirs -maproot=root"); err != nil {
+		t.Fatal("Failed buffering add", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal("Failed committing transaction", err)
+	}
+
+	result, err := client.Fs.ReadFile(client.ExportsFile)
+	if err != nil {
+		t.Fatal("Failed reading exports file", err)
+	}
+
+	expected := `/Users 192.168.64.1 -alldirs -maproot=root
+# BEGIN: my-id
+/Users 192.168.64.2 -alldirs -maproot=root
+# END: my-id
+# BEGIN: my-id2
+/Users 192.168.64.3 -alldirs -maproot=root
+# END: my-id2
+`
+	if string(result) != expected {
+		t.Error("Generates an expected result", string(result))
+	}
+}
+
+func TestTransactionVerifyFailureLeavesFileUntouched(t *testing.T) {
+	original := `/Users 192.168.64.1 -alldirs -maproot=root`
+	client := testClient(original)
+	client.Backend = &fakeBackend{verifyErr: errors.New("export_check: bad path")}
+
+	tx, err := client.Begin()
+	if err != nil {
+		t.Fatal("Failed beginning transaction", err)
+	}
+
+	if err := tx.AddRaw("my-id", "/Users 192.168.64.2 -alldirs -maproot=root"); err != nil {
+		t.Fatal("Failed buffering add", err)
+	}
+
+	if err := tx.Commit(); err == nil {
+		t.Error("Expected Commit to fail verification")
+	}
+
+	result, err := client.Fs.ReadFile(client.ExportsFile)
+	if err != nil {
+		t.Fatal("Failed reading exports file", err)
+	}
+
+	if !bytes.Equal(result, []byte(original)) {
+		t.Error("On-disk exports file was modified despite a failed verification", string(result))
+	}
+}
+
+func TestTransactionReloadFailureRestoresSnapshot(t *testing.T) {
+	original := `/Users 192.168.64.1 -alldirs -maproot=root`
+	client := testClient(original)
+
+	tx, err := client.Begin()
+	if err != nil {
+		t.Fatal("Failed beginning transaction", err)
+	}
+
+	if err := tx.AddRaw("my-id", "/Users 192.168.64.2 -alldirs -maproot=root"); err != nil {
+		t.Fatal("Failed buffering add", err)
+	}
+
+	// Fail only Reload, after Verify/write have already succeeded.
+	tx.client.Backend = &reloadFailingBackend{}
+
+	if err := tx.Commit(); err == nil {
+		t.Error("Expected Commit to fail on Reload")
+	}
+
+	result, err := client.Fs.ReadFile(client.ExportsFile)
+	if err != nil {
+		t.Fatal("Failed reading exports file", err)
+	}
+
+	if !bytes.Equal(result, []byte(original)) {
+		t.Error("On-disk exports file was not restored after a failed reload", string(result))
+	}
+}
+
+func TestTransactionRollbackLeavesFileUntouched(t *testing.T) {
+	original := `/Users 192.168.64.1 -alldirs -maproot=root`
+	client := testClient(original)
+
+	tx, err := client.Begin()
+	if err != nil {
+		t.Fatal("Failed beginning transaction", err)
+	}
+
+	if err := tx.AddRaw("my-id", "/Users 192.168.64.2 -alldirs -maproot=root"); err != nil {
+		t.Fatal("Failed buffering add", err)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal("Failed rolling back transaction", err)
+	}
+
+	result, err := client.Fs.ReadFile(client.ExportsFile)
+	if err != nil {
+		t.Fatal("Failed reading exports file", err)
+	}
+
+	if !bytes.Equal(result, []byte(original)) {
+		t.Error("On-disk exports file was modified by a rolled-back transaction", string(result))
+	}
+}
+
+func TestTransactionUseAfterCommit(t *testing.T) {
+	client := testClient("")
+
+	tx, err := client.Begin()
+	if err != nil {
+		t.Fatal("Failed beginning transaction", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal("Failed committing transaction", err)
+	}
+
+	if err := tx.AddRaw("my-id", "/Users 192.168.64.2 -alldirs -maproot=root"); err != errTransactionClosed {
+		t.Error("Expected errTransactionClosed after Commit", err)
+	}
+}
+
+// reloadFailingBackend commits a successful Verify but fails Reload, to
+// exercise Transaction's restore-on-reload-failure path.
+type reloadFailingBackend struct{}
+
+func (reloadFailingBackend) ValidateSyntax(line string) error { return nil }
+func (reloadFailingBackend) Verify(ctx context.Context, newContents []byte) error {
+	return nil
+}
+func (reloadFailingBackend) Reload(ctx context.Context) error {
+	return errors.New("nfsd update failed")
+}
+func (reloadFailingBackend) Syntax() ExportSyntax { return BSDSyntax }